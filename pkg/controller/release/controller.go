@@ -7,10 +7,10 @@ import (
 	releasev1alpha1 "github.com/caicloud/clientset/kubernetes/typed/release/v1alpha1"
 	listerrelease "github.com/caicloud/clientset/listers/release/v1alpha1"
 	"github.com/caicloud/release-controller/pkg/kube"
+	"github.com/caicloud/release-controller/pkg/log"
 	"github.com/caicloud/release-controller/pkg/release"
 	"github.com/caicloud/release-controller/pkg/render"
 	"github.com/caicloud/release-controller/pkg/storage"
-	"github.com/golang/glog"
 	"k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -18,32 +18,61 @@ import (
 	"k8s.io/client-go/util/workqueue"
 )
 
+// reconcileInterval is how often Run re-runs reconcile after its initial,
+// on-startup pass. It only needs to catch up on pruning a crash
+// interrupted, so it is deliberately far coarser than the per-release
+// work queue.
+const reconcileInterval = 5 * time.Minute
+
 // ReleaseController watches all resource related release and release history.
 type ReleaseController struct {
 	queue            workqueue.RateLimitingInterface
 	manager          release.ReleaseManager
 	releaseLister    listerrelease.ReleaseLister
 	releaseHasSynced cache.InformerSynced
+	Log              log.Func
 }
 
-// NewReleaseController creates a release controller.
+// NewReleaseController creates a release controller. When rudderAddress
+// is non-empty, releases are executed by dialing it as a gRPC Rudder
+// service instead of the in-process ReleaseHandler, letting operators
+// substitute custom deployment logic without forking the controller.
+// defaultMaxHistory bounds how many ReleaseHistory revisions are kept
+// for a Release whose own Spec.MaxHistory is unset; <= 0 means
+// unbounded. postRenderer, if non-nil, runs over every manifest the
+// release module renders before it reaches the cluster or the remote
+// Rudder executor; pass nil to apply manifests unmodified.
 func NewReleaseController(
 	clients kube.ClientPool,
 	codec kube.Codec,
 	releaseClient releasev1alpha1.ReleaseV1alpha1Interface,
 	releaseInformer informerrelease.ReleaseInformer,
+	rudderAddress string,
+	defaultMaxHistory int,
+	postRenderer render.PostRenderer,
 ) (*ReleaseController, error) {
-	client, err := kube.NewClient(clients, codec)
+	logger := log.Default()
+	client, err := kube.NewClient(clients, codec, logger)
 	if err != nil {
 		return nil, err
 	}
-	handler := release.NewReleaseHandler(render.NewRender(), client)
-	backend := storage.NewReleaseBackend(releaseClient)
+	renderer := render.NewRender()
+	backend := storage.NewReleaseBackend(releaseClient, logger)
+	var module release.ReleaseModule
+	if rudderAddress != "" {
+		module, err = release.NewGRPCReleaseModule(rudderAddress, renderer, postRenderer)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		module = release.NewReleaseHandler(renderer, client, logger, postRenderer, backend.SetCondition)
+	}
 	rc := &ReleaseController{
 		queue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
-		manager:          release.NewReleaseManager(backend, handler),
+		manager:          release.NewReleaseManager(backend, module, defaultMaxHistory, logger),
 		releaseLister:    releaseInformer.Lister(),
 		releaseHasSynced: releaseInformer.Informer().HasSynced,
+		Log:              logger,
 	}
 	releaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: rc.enqueueRelease,
@@ -64,40 +93,58 @@ func (rc *ReleaseController) keyForObj(obj interface{}) (string, error) {
 func (rc *ReleaseController) enqueueRelease(obj interface{}) {
 	key, err := rc.keyForObj(obj)
 	if err != nil {
-		glog.Errorf("Can't get obj key: %v", err)
+		rc.Log.Errorf("Can't get obj key: %v", err)
 		return
 	}
 
-	glog.V(4).Infof("Enqueue: %s", key)
+	rc.Log.V(4)("Enqueue: %s", key)
 	// key must be a string
 	rc.queue.Add(key)
 }
 
-// Run starts controller and checks releases
-func (rc *ReleaseController) Run(stopCh <-chan struct{}) {
+// Run starts controller and checks releases. It starts workers goroutines
+// pulling from the shared queue; the workqueue's own de-dup and
+// processing set guarantee a given release is never handed to two
+// workers at once, so concurrency here is safe without any locking in
+// storage.ReleaseBackend.
+func (rc *ReleaseController) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-	glog.Info("Running ReleaseController")
+	rc.Log.Infof("Running ReleaseController")
 
 	if !cache.WaitForCacheSync(stopCh, rc.releaseHasSynced) {
-		glog.Errorf("Can't sync cache")
+		rc.Log.Errorf("Can't sync cache")
 		return
 	}
-	glog.Info("Sync ReleaseController cache successfully")
+	rc.Log.Infof("Sync ReleaseController cache successfully")
 
-	go wait.Until(rc.worker, time.Second, stopCh)
+	go wait.Until(rc.reconcile, reconcileInterval, stopCh)
+	for i := 0; i < workers; i++ {
+		go wait.Until(rc.processQueue, time.Second, stopCh)
+	}
 
 	<-stopCh
-	glog.Info("Shutting down ReleaseController")
+	rc.Log.Infof("Shutting down ReleaseController")
 }
 
-// worker checks improper resources. If controller unexpectedly terminated,
-// some resources may not delete completely. worker should detect those
-// resources and let them in a correct posture.
-func (rc *ReleaseController) worker() {
+// reconcile checks improper resources. If controller unexpectedly
+// terminated, some resources may not delete completely. reconcile should
+// detect those resources and let them in a correct posture. It also
+// prunes ReleaseHistory revisions beyond each release's MaxHistory, so
+// restarts catch up on trimming that a crash interrupted. Run fires it
+// once immediately on startup and then every reconcileInterval -- a full
+// ListReleases/ListHistories sweep is too expensive to run every second.
+// It always runs on a single goroutine, independent of how many workers
+// process the queue.
+func (rc *ReleaseController) reconcile() {
 	if err := rc.manager.Run(); err != nil {
-		glog.Errorf("Can't run manager: %v", err)
+		rc.Log.Errorf("Can't run manager: %v", err)
 	}
-	glog.V(3).Infof("Processing ReleaseController releases")
+}
+
+// processQueue drains the work queue, handling one release at a time.
+// Multiple workers may run this concurrently.
+func (rc *ReleaseController) processQueue() {
+	rc.Log.V(3)("Processing ReleaseController releases")
 	for rc.processNextWorkItem() {
 	}
 }
@@ -106,19 +153,19 @@ func (rc *ReleaseController) worker() {
 func (rc *ReleaseController) processNextWorkItem() bool {
 	key, quit := rc.queue.Get()
 	if quit {
-		glog.Error("Unexpected quit of release queue")
+		rc.Log.Errorf("Unexpected quit of release queue")
 		return false
 	}
 	defer rc.queue.Done(key)
-	glog.V(4).Infof("Handle release by key: %s", key)
+	rc.Log.V(4)("Handle release by key: %s", key)
 	namespace, name, err := cache.SplitMetaNamespaceKey(key.(string))
 	if err != nil {
-		glog.Errorf("Can't recognize key of release: %s", key)
+		rc.Log.Errorf("Can't recognize key of release: %s", key)
 		return false
 	}
 	release, err := rc.releaseLister.Releases(namespace).Get(name)
 	if err != nil && !errors.IsNotFound(err) {
-		glog.Errorf("Can't get release: %s", key)
+		rc.Log.Errorf("Can't get release: %s", key)
 		return false
 	}
 	if err != nil {
@@ -131,9 +178,9 @@ func (rc *ReleaseController) processNextWorkItem() bool {
 	if err != nil {
 		// Re-enqueue
 		rc.queue.AddRateLimited(key)
-		glog.Errorf("Can't handle release: %+v", release)
+		rc.Log.Errorf("Can't handle release: %+v", release)
 		return false
 	}
-	glog.V(4).Infof("Handled release: %s", key)
+	rc.Log.V(4)("Handled release: %s", key)
 	return true
-}
\ No newline at end of file
+}
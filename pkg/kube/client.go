@@ -0,0 +1,173 @@
+package kube
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/caicloud/release-controller/pkg/log"
+)
+
+// Resource is a single object decoded from a rendered manifest, paired
+// with the name/namespace/kind the manifest declared for it. GVK is
+// captured once at decode time and kept alongside Object so callers that
+// overwrite Object with a Create/Update/Get response -- which, for typed
+// client-go objects, usually comes back with an empty TypeMeta -- can
+// still resolve a ResourceClient for it later.
+type Resource struct {
+	Name   string
+	Kind   string
+	GVK    schema.GroupVersionKind
+	Object runtime.Object
+}
+
+// ClientPool resolves the REST client to use for a given
+// GroupVersionKind, so Client can apply any resource found in a rendered
+// manifest without a scheme compiled in ahead of time.
+type ClientPool interface {
+	ClientForGroupVersionKind(gvk schema.GroupVersionKind) (ResourceClient, error)
+}
+
+// ResourceClient is the minimal per-GVK surface Client needs to apply a
+// manifest: create, update, delete and get by namespace/name.
+type ResourceClient interface {
+	Create(namespace string, obj runtime.Object) (runtime.Object, error)
+	Update(namespace string, obj runtime.Object) (runtime.Object, error)
+	Delete(namespace, name string) error
+	Get(namespace, name string) (runtime.Object, error)
+}
+
+// Codec decodes the raw YAML/JSON manifest produced by pkg/render into
+// typed Kubernetes objects.
+type Codec interface {
+	Decode(manifest []byte) ([]Resource, error)
+}
+
+// Client applies rendered manifests against a cluster.
+type Client struct {
+	pool  ClientPool
+	codec Codec
+	Log   log.Func
+}
+
+// NewClient creates a Client that resolves resources through pool and
+// decodes manifests with codec, logging through logger.
+func NewClient(pool ClientPool, codec Codec, logger log.Func) (*Client, error) {
+	return &Client{pool: pool, codec: codec, Log: logger}, nil
+}
+
+// Create decodes manifest and creates every resource it contains,
+// returning the resources that were created so callers can wait on them.
+func (c *Client) Create(namespace string, manifest []byte) ([]Resource, error) {
+	resources, err := c.codec.Decode(manifest)
+	if err != nil {
+		return nil, err
+	}
+	c.Log.Infof("building resources from manifest")
+	c.Log.Infof("creating %d resource(s)", len(resources))
+	for i, r := range resources {
+		gvk := r.Object.GetObjectKind().GroupVersionKind()
+		rc, err := c.pool.ClientForGroupVersionKind(gvk)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := rc.Create(namespace, r.Object)
+		if err != nil {
+			return nil, err
+		}
+		resources[i].GVK = gvk
+		resources[i].Object = obj
+	}
+	return resources, nil
+}
+
+// Update decodes manifest and updates every resource it contains in
+// place, creating any that don't yet exist. It returns the resources that
+// were applied so callers can wait on them.
+func (c *Client) Update(namespace string, manifest []byte) ([]Resource, error) {
+	resources, err := c.codec.Decode(manifest)
+	if err != nil {
+		return nil, err
+	}
+	c.Log.Infof("building resources from manifest")
+	c.Log.Infof("checking %d resources for changes", len(resources))
+	for i, r := range resources {
+		gvk := r.Object.GetObjectKind().GroupVersionKind()
+		rc, err := c.pool.ClientForGroupVersionKind(gvk)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := rc.Update(namespace, r.Object)
+		if apierrors.IsNotFound(err) {
+			obj, err = rc.Create(namespace, r.Object)
+		}
+		if err != nil {
+			return nil, err
+		}
+		resources[i].GVK = gvk
+		resources[i].Object = obj
+	}
+	return resources, nil
+}
+
+// Get decodes manifest and fetches the live copy of every resource it
+// names, without creating or modifying anything. It's used to check
+// status on resources a ReleaseModule applied out-of-band.
+func (c *Client) Get(namespace string, manifest []byte) ([]Resource, error) {
+	resources, err := c.codec.Decode(manifest)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resources {
+		gvk := r.Object.GetObjectKind().GroupVersionKind()
+		rc, err := c.pool.ClientForGroupVersionKind(gvk)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := rc.Get(namespace, r.Name)
+		if err != nil {
+			return nil, err
+		}
+		resources[i].GVK = gvk
+		resources[i].Object = obj
+	}
+	return resources, nil
+}
+
+// RefreshResource fetches the live copy of a single resource, keyed by
+// r.GVK and r.Name. It uses r.GVK rather than r.Object's own
+// GroupVersionKind because Object may already be a Create/Update/Get
+// response -- typed client-go objects usually come back with an empty
+// TypeMeta -- so Waiter can re-check status on each poll tick against
+// the exact resources a prior Create/Update returned, rather than
+// polling their now-stale snapshot.
+func (c *Client) RefreshResource(namespace string, r Resource) (Resource, error) {
+	rc, err := c.pool.ClientForGroupVersionKind(r.GVK)
+	if err != nil {
+		return Resource{}, err
+	}
+	obj, err := rc.Get(namespace, r.Name)
+	if err != nil {
+		return Resource{}, err
+	}
+	r.Object = obj
+	return r, nil
+}
+
+// Delete decodes manifest and deletes every resource it contains.
+func (c *Client) Delete(namespace string, manifest []byte) error {
+	resources, err := c.codec.Decode(manifest)
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		rc, err := c.pool.ClientForGroupVersionKind(r.Object.GetObjectKind().GroupVersionKind())
+		if err != nil {
+			return err
+		}
+		if err := rc.Delete(namespace, r.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// pollInterval is how often Waiter re-checks resource status while
+// waiting for a release to stabilize.
+const pollInterval = 2 * time.Second
+
+// Waiter polls a set of resources until they all report ready or a
+// timeout elapses. It understands the rollout semantics of Deployment,
+// StatefulSet, DaemonSet and Job, the same checks Helm performs in
+// kube/wait.go before an upgrade is considered successful.
+type Waiter struct {
+	client  *Client
+	timeout time.Duration
+}
+
+// NewWaiter returns a Waiter bound to client that gives up after timeout.
+func NewWaiter(client *Client, timeout time.Duration) *Waiter {
+	return &Waiter{client: client, timeout: timeout}
+}
+
+// WaitForResources blocks until every resource in resources is ready, or
+// returns an error once the Waiter's timeout elapses. resources is only
+// used to identify what to poll (name and GVK, within namespace); each tick re-fetches
+// the live object through w.client rather than trusting the Create/Update
+// response captured before the poll began, which never gains a status.
+func (w *Waiter) WaitForResources(namespace string, resources []Resource) error {
+	return wait.PollImmediate(pollInterval, w.timeout, func() (bool, error) {
+		for _, r := range resources {
+			live, err := w.client.RefreshResource(namespace, r)
+			if err != nil {
+				return false, err
+			}
+			ready, err := IsReady(live)
+			if err != nil {
+				return false, err
+			}
+			if !ready {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// IsReady reports whether r has satisfied its expected replica count. Any
+// kind Waiter doesn't know how to probe is treated as ready immediately.
+// It is exported so callers that only need a point-in-time check, such
+// as ReleaseHandler.ReleaseStatus, don't need to poll through a Waiter.
+func IsReady(r Resource) (bool, error) {
+	switch obj := r.Object.(type) {
+	case *apps.Deployment:
+		return deploymentReady(obj), nil
+	case *apps.StatefulSet:
+		return statefulSetReady(obj), nil
+	case *apps.DaemonSet:
+		return daemonSetReady(obj), nil
+	case *batch.Job:
+		return jobReady(obj), nil
+	default:
+		return true, nil
+	}
+}
+
+func deploymentReady(dep *apps.Deployment) bool {
+	if dep.Status.ObservedGeneration < dep.Generation {
+		// The status still reflects the previous revision; wait for
+		// the controller to observe the new spec before trusting it.
+		return false
+	}
+	expected := int32(1)
+	if dep.Spec.Replicas != nil {
+		expected = *dep.Spec.Replicas
+	}
+	// Status.Replicas (the total, including a surge replica still
+	// terminating during a RollingUpdate) is deliberately not checked --
+	// Helm's wait only requires updated/available to reach expected.
+	return dep.Status.UpdatedReplicas == expected &&
+		dep.Status.AvailableReplicas == expected
+}
+
+func statefulSetReady(sts *apps.StatefulSet) bool {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false
+	}
+	expected := int32(1)
+	if sts.Spec.Replicas != nil {
+		expected = *sts.Spec.Replicas
+	}
+	return sts.Status.UpdatedReplicas == expected &&
+		sts.Status.ReadyReplicas == expected
+}
+
+func daemonSetReady(ds *apps.DaemonSet) bool {
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return false
+	}
+	return ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberReady == ds.Status.DesiredNumberScheduled
+}
+
+func jobReady(job *batch.Job) bool {
+	return job.Status.Succeeded > 0
+}
+
+// TimeoutError wraps the error that ended a wait for a release's
+// resources -- the wait.PollImmediate timeout, or a failure to fetch or
+// probe a resource -- with the name of the release that failed to
+// stabilize, so callers can attribute it without string-matching the
+// underlying error.
+type TimeoutError struct {
+	Release string
+	Cause   error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("release %s did not become ready: %v", e.Release, e.Cause)
+}
@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caicloud/release-controller/pkg/log"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// notReadyThenReadyClient is a ResourceClient whose Get reports a
+// Deployment with no available replicas on its first call and a fully
+// rolled-out Deployment on every call after, so a test can distinguish a
+// Waiter that re-fetches live status from one that only inspects the
+// object it was first given.
+type notReadyThenReadyClient struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *notReadyThenReadyClient) Create(namespace string, obj runtime.Object) (runtime.Object, error) {
+	return obj, nil
+}
+func (c *notReadyThenReadyClient) Update(namespace string, obj runtime.Object) (runtime.Object, error) {
+	return obj, nil
+}
+func (c *notReadyThenReadyClient) Delete(namespace, name string) error { return nil }
+
+func (c *notReadyThenReadyClient) Get(namespace, name string) (runtime.Object, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+
+	replicas := int32(1)
+	dep := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Generation: 1},
+		Spec:       apps.DeploymentSpec{Replicas: &replicas},
+		Status:     apps.DeploymentStatus{ObservedGeneration: 1},
+	}
+	if c.calls > 1 {
+		dep.Status.UpdatedReplicas = 1
+		dep.Status.AvailableReplicas = 1
+	}
+	return dep, nil
+}
+
+func (c *notReadyThenReadyClient) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// singleClientPool always resolves to the same ResourceClient, regardless
+// of GroupVersionKind.
+type singleClientPool struct {
+	client ResourceClient
+}
+
+func (p singleClientPool) ClientForGroupVersionKind(gvk schema.GroupVersionKind) (ResourceClient, error) {
+	return p.client, nil
+}
+
+// TestWaitForResourcesRefetchesLiveStatus checks that WaitForResources
+// polls live status through the Client rather than re-evaluating the
+// Create/Update response it was handed, which never gains a status.
+func TestWaitForResourcesRefetchesLiveStatus(t *testing.T) {
+	rc := &notReadyThenReadyClient{}
+	client, err := NewClient(singleClientPool{client: rc}, nil, log.Noop())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	replicas := int32(1)
+	resource := Resource{
+		Name: "app",
+		Kind: "Deployment",
+		GVK:  schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Object: &apps.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Generation: 1},
+			Spec:       apps.DeploymentSpec{Replicas: &replicas},
+		},
+	}
+
+	waiter := NewWaiter(client, 10*time.Second)
+	if err := waiter.WaitForResources("default", []Resource{resource}); err != nil {
+		t.Fatalf("WaitForResources: %v", err)
+	}
+
+	if calls := rc.callCount(); calls < 2 {
+		t.Errorf("expected WaitForResources to re-fetch status more than once before succeeding, got %d call(s)", calls)
+	}
+}
@@ -0,0 +1,157 @@
+package release
+
+import (
+	"context"
+	"time"
+
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+	"github.com/caicloud/release-controller/pkg/render"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// dialTimeout bounds how long NewGRPCReleaseModule waits for the initial
+// connection to the external executor.
+const dialTimeout = 5 * time.Second
+
+// rudderService is the gRPC service name the external executor must
+// implement, named after Helm's Rudder component, the original
+// out-of-process release executor this design is modeled on.
+const rudderService = "/rudder.Rudder/"
+
+// ReleaseRequest is the payload sent for every RPC: everything an
+// external executor needs to act on a release without importing the
+// release CRD types itself. Its field tags mirror what protoc-gen-go
+// would emit for a rudder.proto declaring the same fields, so it can be
+// marshaled by grpc's default proto codec without a generated .pb.go.
+type ReleaseRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Manifest  []byte `protobuf:"bytes,3,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	Previous  []byte `protobuf:"bytes,4,opt,name=previous,proto3" json:"previous,omitempty"`
+}
+
+func (m *ReleaseRequest) Reset()         { *m = ReleaseRequest{} }
+func (m *ReleaseRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseRequest) ProtoMessage()    {}
+
+// ReleaseResponse is the result of a RudderClient RPC.
+type ReleaseResponse struct {
+	Status string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+func (m *ReleaseResponse) Reset()         { *m = ReleaseResponse{} }
+func (m *ReleaseResponse) String() string { return proto.CompactTextString(m) }
+func (*ReleaseResponse) ProtoMessage()    {}
+
+// GRPCReleaseModule is a ReleaseModule that delegates to an external
+// executor over gRPC, so operators can substitute custom deployment
+// logic -- e.g. progressive delivery, service-mesh-aware rollouts, or
+// bespoke schedulers -- without forking the controller.
+type GRPCReleaseModule struct {
+	render       *render.Render
+	postRenderer render.PostRenderer
+	conn         *grpc.ClientConn
+}
+
+// NewGRPCReleaseModule dials address and returns a ReleaseModule backed
+// by the resulting connection. r renders manifests locally; only their
+// execution is delegated to the remote end. postRenderer, if non-nil,
+// runs over every manifest before it is sent to the remote executor, the
+// same as it would for the in-process ReleaseHandler; pass nil to send
+// manifests unmodified. Dialing blocks for up to dialTimeout so a dead
+// address fails construction instead of surfacing only on the first RPC.
+func NewGRPCReleaseModule(address string, r *render.Render, postRenderer render.PostRenderer) (*GRPCReleaseModule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCReleaseModule{render: r, postRenderer: postRenderer, conn: conn}, nil
+}
+
+// InstallRelease renders release and asks the remote executor to
+// install the resulting manifest.
+func (m *GRPCReleaseModule) InstallRelease(release *releaseapi.Release) error {
+	return m.call("InstallRelease", release, nil)
+}
+
+// UpgradeRelease renders release and asks the remote executor to apply
+// the resulting manifest in place of a prior revision's.
+func (m *GRPCReleaseModule) UpgradeRelease(release *releaseapi.Release) error {
+	return m.call("UpgradeRelease", release, nil)
+}
+
+// RollbackRelease asks the remote executor to restore release to
+// previous. previous is nil when there is no earlier revision.
+func (m *GRPCReleaseModule) RollbackRelease(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error {
+	return m.call("RollbackRelease", release, previous)
+}
+
+// DeleteRelease asks the remote executor to remove release's resources.
+func (m *GRPCReleaseModule) DeleteRelease(release *releaseapi.Release) error {
+	return m.call("DeleteRelease", release, nil)
+}
+
+// ReleaseStatus asks the remote executor for release's current status.
+func (m *GRPCReleaseModule) ReleaseStatus(release *releaseapi.Release) (string, error) {
+	req, err := m.request(release, nil)
+	if err != nil {
+		return "", err
+	}
+	resp := new(ReleaseResponse)
+	if err := m.conn.Invoke(context.Background(), rudderService+"ReleaseStatus", req, resp); err != nil {
+		return "", err
+	}
+	return resp.Status, nil
+}
+
+// call renders release (and previous, if given) and invokes method on
+// the remote executor, discarding its response.
+func (m *GRPCReleaseModule) call(method string, release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error {
+	req, err := m.request(release, previous)
+	if err != nil {
+		return err
+	}
+	return m.conn.Invoke(context.Background(), rudderService+method, req, new(ReleaseResponse))
+}
+
+// request renders release (and previous, if given) into a ReleaseRequest,
+// running every manifest through the configured PostRenderer.
+func (m *GRPCReleaseModule) request(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) (*ReleaseRequest, error) {
+	manifest, err := m.render.Render(release)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err = m.postRender(manifest)
+	if err != nil {
+		return nil, err
+	}
+	req := &ReleaseRequest{
+		Namespace: release.Namespace,
+		Name:      release.Name,
+		Manifest:  manifest,
+	}
+	if previous != nil {
+		prev, err := m.render.RenderTemplate(previous.Spec.Template, previous.Spec.Config)
+		if err != nil {
+			return nil, err
+		}
+		prev, err = m.postRender(prev)
+		if err != nil {
+			return nil, err
+		}
+		req.Previous = prev
+	}
+	return req, nil
+}
+
+// postRender runs manifest through the configured PostRenderer. A nil
+// PostRenderer is a passthrough.
+func (m *GRPCReleaseModule) postRender(manifest []byte) ([]byte, error) {
+	if m.postRenderer == nil {
+		return manifest, nil
+	}
+	return m.postRenderer.Run(manifest)
+}
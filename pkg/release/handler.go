@@ -0,0 +1,172 @@
+package release
+
+import (
+	"time"
+
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+	"github.com/caicloud/release-controller/pkg/kube"
+	"github.com/caicloud/release-controller/pkg/log"
+	"github.com/caicloud/release-controller/pkg/render"
+	"github.com/caicloud/release-controller/pkg/storage"
+)
+
+// defaultWaitTimeout is used when a Release has Spec.Wait or Spec.Atomic
+// set but leaves Spec.Timeout at its zero value, in seconds.
+const defaultWaitTimeout = 5 * 60
+
+// ReleaseHandler is the default, in-process ReleaseModule. It renders a
+// Release's manifest, runs it through an optional PostRenderer, and
+// applies the result to the cluster directly, optionally waiting for the
+// resulting resources to become ready before returning.
+type ReleaseHandler struct {
+	render       *render.Render
+	client       *kube.Client
+	postRenderer render.PostRenderer
+	setCondition func(release *releaseapi.Release, condition releaseapi.ReleaseCondition) error
+	Log          log.Func
+}
+
+// NewReleaseHandler creates a ReleaseHandler that renders manifests with
+// r and applies them through client, logging through logger. postRenderer
+// may be nil, in which case rendered manifests are applied unmodified.
+// setCondition is called to record the intermediate WaitingForReady
+// condition before InstallRelease/UpgradeRelease blocks on readiness,
+// matching storage.ReleaseBackend.SetCondition's signature.
+func NewReleaseHandler(r *render.Render, client *kube.Client, logger log.Func, postRenderer render.PostRenderer, setCondition func(release *releaseapi.Release, condition releaseapi.ReleaseCondition) error) *ReleaseHandler {
+	return &ReleaseHandler{render: r, client: client, postRenderer: postRenderer, setCondition: setCondition, Log: logger}
+}
+
+// InstallRelease renders release and creates its resources. If Spec.Wait
+// or Spec.Atomic is set, InstallRelease blocks until the resources
+// report ready or Spec.Timeout elapses.
+func (h *ReleaseHandler) InstallRelease(release *releaseapi.Release) error {
+	manifest, err := h.renderManifest(release)
+	if err != nil {
+		return err
+	}
+	resources, err := h.client.Create(release.Namespace, manifest)
+	if err != nil {
+		return err
+	}
+	return h.waitIfNeeded(release, resources)
+}
+
+// UpgradeRelease renders release and applies its resources in place.
+// Like InstallRelease, it honors Spec.Wait, Spec.Atomic and
+// Spec.Timeout.
+func (h *ReleaseHandler) UpgradeRelease(release *releaseapi.Release) error {
+	manifest, err := h.renderManifest(release)
+	if err != nil {
+		return err
+	}
+	resources, err := h.client.Update(release.Namespace, manifest)
+	if err != nil {
+		return err
+	}
+	return h.waitIfNeeded(release, resources)
+}
+
+// DeleteRelease removes all resources rendered from release.
+func (h *ReleaseHandler) DeleteRelease(release *releaseapi.Release) error {
+	manifest, err := h.renderManifest(release)
+	if err != nil {
+		return err
+	}
+	return h.client.Delete(release.Namespace, manifest)
+}
+
+// RollbackRelease restores release to previous's rendered manifest. If
+// previous is nil there is no earlier revision to restore, so
+// RollbackRelease deletes release's resources instead, matching Helm's
+// behavior for a failed first install -- unless release.Spec.CleanupOnFail
+// is false, in which case the failed resources are left in place for
+// inspection.
+func (h *ReleaseHandler) RollbackRelease(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error {
+	if previous == nil {
+		if !release.Spec.CleanupOnFail {
+			return nil
+		}
+		return h.DeleteRelease(release)
+	}
+	manifest, err := h.render.RenderTemplate(previous.Spec.Template, previous.Spec.Config)
+	if err != nil {
+		return err
+	}
+	manifest, err = h.postRender(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = h.client.Update(release.Namespace, manifest)
+	return err
+}
+
+// ReleaseStatus reports whether release's live resources are ready. It
+// exists mainly to satisfy ReleaseModule for backends, like
+// GRPCReleaseModule, where readiness can't be observed synchronously
+// within Install/UpgradeRelease.
+func (h *ReleaseHandler) ReleaseStatus(release *releaseapi.Release) (string, error) {
+	manifest, err := h.renderManifest(release)
+	if err != nil {
+		return "", err
+	}
+	resources, err := h.client.Get(release.Namespace, manifest)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range resources {
+		ready, err := kube.IsReady(r)
+		if err != nil {
+			return "", err
+		}
+		if !ready {
+			return storage.ReasonWaitingForReady, nil
+		}
+	}
+	return storage.ReasonAvailable, nil
+}
+
+// renderManifest renders release and runs the result through the
+// configured PostRenderer, if any.
+func (h *ReleaseHandler) renderManifest(release *releaseapi.Release) ([]byte, error) {
+	manifest, err := h.render.Render(release)
+	if err != nil {
+		return nil, err
+	}
+	return h.postRender(manifest)
+}
+
+// postRender runs manifest through the configured PostRenderer. A nil
+// PostRenderer is a passthrough.
+func (h *ReleaseHandler) postRender(manifest []byte) ([]byte, error) {
+	if h.postRenderer == nil {
+		return manifest, nil
+	}
+	return h.postRenderer.Run(manifest)
+}
+
+// waitIfNeeded blocks until resources are ready when release opts into
+// Spec.Wait or Spec.Atomic, and is a no-op otherwise.
+func (h *ReleaseHandler) waitIfNeeded(release *releaseapi.Release, resources []kube.Resource) error {
+	if !release.Spec.Wait && !release.Spec.Atomic {
+		return nil
+	}
+	if err := h.setCondition(release, storage.ConditionWaitingForReady("")); err != nil {
+		return err
+	}
+	timeout := release.Spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	h.Log.Infof("%s/%s: waiting for release to stabilize", release.Namespace, release.Name)
+	waiter := kube.NewWaiter(h.client, timeoutDuration(timeout))
+	if err := waiter.WaitForResources(release.Namespace, resources); err != nil {
+		return &kube.TimeoutError{Release: release.Namespace + "/" + release.Name, Cause: err}
+	}
+	return nil
+}
+
+// timeoutDuration converts a Spec.Timeout value, expressed in seconds, to
+// a time.Duration.
+func timeoutDuration(seconds int64) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,44 @@
+package release
+
+import "sync"
+
+// keyedMutex hands out a distinct lock per key, so callers can serialize
+// work on one key (a Release's namespace/name) without blocking work on
+// unrelated keys. Wrapping Trigger in one means ReleaseManager stays
+// safe even if ReleaseController runs multiple workers against a shared
+// queue, without adding any locking to storage.ReleaseBackend.
+//
+// Locks are never removed once created; the map grows with the number
+// of distinct releases seen over the controller's lifetime, which is
+// bounded in practice and cheap to keep around.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// newKeyedMutex returns an empty keyedMutex.
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*sync.Mutex)}
+}
+
+// Lock blocks until key is uncontended, then locks it.
+func (k *keyedMutex) Lock(key string) {
+	k.lockFor(key).Lock()
+}
+
+// Unlock unlocks key.
+func (k *keyedMutex) Unlock(key string) {
+	k.lockFor(key).Unlock()
+}
+
+// lockFor returns the *sync.Mutex for key, creating it if necessary.
+func (k *keyedMutex) lockFor(key string) *sync.Mutex {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		k.locks[key] = lock
+	}
+	return lock
+}
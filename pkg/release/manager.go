@@ -0,0 +1,169 @@
+package release
+
+import (
+	"fmt"
+
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+	"github.com/caicloud/release-controller/pkg/log"
+	"github.com/caicloud/release-controller/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReleaseManager drives the lifecycle of a single Release: turning a
+// desired spec into running resources, rolling back failures and
+// cleaning up deleted releases.
+type ReleaseManager interface {
+	// Run performs a reconciliation pass over releases that may have been
+	// left in an inconsistent state by a previous controller crash.
+	Run() error
+	// Trigger creates or updates the resources for release, depending on
+	// whether it has a prior revision.
+	Trigger(release *releaseapi.Release) error
+	// Delete removes all resources owned by the release identified by
+	// namespace/name.
+	Delete(namespace, name string) error
+}
+
+// releaseManager is the default ReleaseManager. It persists release
+// state through backend and applies resources through module.
+type releaseManager struct {
+	backend           storage.ReleaseBackend
+	module            ReleaseModule
+	defaultMaxHistory int
+	Log               log.Func
+	locks             *keyedMutex
+}
+
+// NewReleaseManager creates a ReleaseManager backed by backend that
+// executes releases through module. module is chosen at controller
+// construction time, so it may be the in-process ReleaseHandler or a
+// GRPCReleaseModule dialing an external executor. defaultMaxHistory
+// bounds how many ReleaseHistory revisions are kept for a Release whose
+// own Spec.MaxHistory is unset; <= 0 means unbounded. logger receives
+// every lifecycle message the manager emits. Trigger serializes per
+// release namespace/name, so NewReleaseManager is safe to share across
+// however many concurrent workers ReleaseController.Run starts.
+func NewReleaseManager(backend storage.ReleaseBackend, module ReleaseModule, defaultMaxHistory int, logger log.Func) ReleaseManager {
+	return &releaseManager{
+		backend:           backend,
+		module:            module,
+		defaultMaxHistory: defaultMaxHistory,
+		Log:               logger,
+		locks:             newKeyedMutex(),
+	}
+}
+
+// Run performs a reconciliation pass over every known release: pruning
+// any ReleaseHistory revisions left over MaxHistory by a previous
+// controller crash, or simply not yet trimmed since.
+func (m *releaseManager) Run() error {
+	releases, err := m.backend.ListReleases()
+	if err != nil {
+		return err
+	}
+	for _, release := range releases {
+		if err := m.pruneHistory(release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneHistory trims release's ReleaseHistory revisions down to its
+// Spec.MaxHistory, falling back to the manager's default when unset.
+func (m *releaseManager) pruneHistory(release *releaseapi.Release) error {
+	max := release.Spec.MaxHistory
+	if max <= 0 {
+		max = m.defaultMaxHistory
+	}
+	return m.backend.PruneHistory(release.Namespace, release.Name, max)
+}
+
+// Trigger creates or updates release's resources. When release.Spec.Atomic
+// is set and the create/update fails or its resources never become
+// ready, Trigger automatically rolls back to the previous revision before
+// returning the original error. With no previous revision, the module's
+// RollbackRelease decides what happens: ReleaseHandler deletes the
+// resources only when release.Spec.CleanupOnFail is set.
+func (m *releaseManager) Trigger(release *releaseapi.Release) error {
+	key := release.Namespace + "/" + release.Name
+	m.locks.Lock(key)
+	defer m.locks.Unlock(key)
+
+	previous, err := m.backend.GetLatestHistory(release.Namespace, release.Name)
+	if err != nil {
+		return err
+	}
+
+	revision := int64(1)
+	if previous != nil {
+		revision = previous.Spec.Revision + 1
+	}
+	rlog := releaseLog(m.Log, release, revision)
+
+	apply := m.module.UpgradeRelease
+	condition := storage.ConditionUpdating()
+	if previous == nil {
+		apply = m.module.InstallRelease
+		condition = storage.ConditionCreating()
+	}
+	rlog.Infof("%s", condition.Reason)
+	if err := m.backend.SetCondition(release, condition); err != nil {
+		return err
+	}
+
+	if err := apply(release); err != nil {
+		if release.Spec.Atomic {
+			return m.rollback(release, previous, rlog, err)
+		}
+		rlog.Errorf("release failed: %v", err)
+		if cerr := m.backend.SetCondition(release, storage.ConditionFailure(err.Error())); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+
+	if err := m.pruneHistory(release); err != nil {
+		return err
+	}
+	rlog.Infof("release available")
+	return m.backend.SetCondition(release, storage.ConditionAvailable())
+}
+
+// rollback records the failure that triggered it, restores release to
+// previous (or deletes its resources if previous is nil), and returns the
+// original cause so the controller re-enqueues the release.
+func (m *releaseManager) rollback(release *releaseapi.Release, previous *releaseapi.ReleaseHistory, rlog log.Func, cause error) error {
+	rlog.Errorf("release failed, rolling back: %v", cause)
+	if err := m.backend.SetCondition(release, storage.ConditionFailure(cause.Error())); err != nil {
+		return err
+	}
+	if err := m.backend.SetCondition(release, storage.ConditionRollbacking()); err != nil {
+		return err
+	}
+	if err := m.module.RollbackRelease(release, previous); err != nil {
+		return err
+	}
+	return cause
+}
+
+// releaseLog returns a Func that prefixes every message with release's
+// namespace/name and revision, so a full install -> wait -> update ->
+// rollback lifecycle can be grepped from logs.
+func releaseLog(logger log.Func, release *releaseapi.Release, revision int64) log.Func {
+	return logger.WithPrefix(fmt.Sprintf("%s/%s@%d: ", release.Namespace, release.Name, revision))
+}
+
+// Delete removes all resources owned by the release identified by
+// namespace/name. It serializes on the same key as Trigger, so a delete
+// can never interleave with an in-flight create/update of the same
+// release.
+func (m *releaseManager) Delete(namespace, name string) error {
+	key := namespace + "/" + name
+	m.locks.Lock(key)
+	defer m.locks.Unlock(key)
+
+	return m.module.DeleteRelease(&releaseapi.Release{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	})
+}
@@ -0,0 +1,163 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+	"github.com/caicloud/release-controller/pkg/log"
+	"github.com/caicloud/release-controller/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeBackend is a no-op storage.ReleaseBackend: these tests care about
+// Trigger's concurrency behavior, not persistence.
+type fakeBackend struct{}
+
+func (fakeBackend) GetLatestHistory(namespace, name string) (*releaseapi.ReleaseHistory, error) {
+	return nil, nil
+}
+func (fakeBackend) SetCondition(release *releaseapi.Release, condition releaseapi.ReleaseCondition) error {
+	return nil
+}
+func (fakeBackend) PruneHistory(namespace, name string, max int) error { return nil }
+func (fakeBackend) ListReleases() ([]*releaseapi.Release, error)      { return nil, nil }
+
+func newRelease(namespace, name string) *releaseapi.Release {
+	return &releaseapi.Release{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+// recordingModule records whether a call for a given release key was
+// already in flight when a new call for that key started, so a test can
+// detect interleaving.
+type recordingModule struct {
+	mu       sync.Mutex
+	inFlight map[string]bool
+	overlaps int
+}
+
+func newRecordingModule() *recordingModule {
+	return &recordingModule{inFlight: make(map[string]bool)}
+}
+
+func (m *recordingModule) InstallRelease(release *releaseapi.Release) error { return m.run(release) }
+func (m *recordingModule) UpgradeRelease(release *releaseapi.Release) error { return m.run(release) }
+func (m *recordingModule) RollbackRelease(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error {
+	return nil
+}
+func (m *recordingModule) DeleteRelease(release *releaseapi.Release) error { return nil }
+func (m *recordingModule) ReleaseStatus(release *releaseapi.Release) (string, error) {
+	return storage.ReasonAvailable, nil
+}
+
+func (m *recordingModule) run(release *releaseapi.Release) error {
+	key := release.Namespace + "/" + release.Name
+	m.mu.Lock()
+	if m.inFlight[key] {
+		m.overlaps++
+	}
+	m.inFlight[key] = true
+	m.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	m.mu.Lock()
+	m.inFlight[key] = false
+	m.mu.Unlock()
+	return nil
+}
+
+// TestTriggerSerializesSameRelease checks that rapid, concurrent Trigger
+// calls for the same Release never run the underlying module
+// concurrently.
+func TestTriggerSerializesSameRelease(t *testing.T) {
+	module := newRecordingModule()
+	manager := NewReleaseManager(fakeBackend{}, module, 0, log.Noop())
+	release := newRelease("default", "app")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := manager.Trigger(release); err != nil {
+				t.Errorf("Trigger: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	module.mu.Lock()
+	defer module.mu.Unlock()
+	if module.overlaps != 0 {
+		t.Errorf("expected no overlapping Trigger calls for the same release, got %d", module.overlaps)
+	}
+}
+
+// barrierModule blocks each call until exactly two calls are in flight
+// at once, failing if that never happens within the timeout. It is used
+// to prove two different releases run in parallel rather than being
+// serialized against each other.
+type barrierModule struct {
+	mu      sync.Mutex
+	arrived int
+	release chan struct{}
+}
+
+func newBarrierModule() *barrierModule {
+	return &barrierModule{release: make(chan struct{})}
+}
+
+func (m *barrierModule) InstallRelease(release *releaseapi.Release) error { return m.run() }
+func (m *barrierModule) UpgradeRelease(release *releaseapi.Release) error { return m.run() }
+func (m *barrierModule) RollbackRelease(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error {
+	return nil
+}
+func (m *barrierModule) DeleteRelease(release *releaseapi.Release) error { return nil }
+func (m *barrierModule) ReleaseStatus(release *releaseapi.Release) (string, error) {
+	return storage.ReasonAvailable, nil
+}
+
+func (m *barrierModule) run() error {
+	m.mu.Lock()
+	m.arrived++
+	if m.arrived == 2 {
+		close(m.release)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.release:
+		return nil
+	case <-time.After(500 * time.Millisecond):
+		return errors.New("timed out waiting for the other release to start concurrently")
+	}
+}
+
+// TestTriggerRunsDifferentReleasesInParallel checks that Trigger for two
+// different releases is not serialized against each other.
+func TestTriggerRunsDifferentReleasesInParallel(t *testing.T) {
+	module := newBarrierModule()
+	manager := NewReleaseManager(fakeBackend{}, module, 0, log.Noop())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		release := newRelease("default", fmt.Sprintf("app-%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- manager.Trigger(release)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Trigger: %v", err)
+		}
+	}
+}
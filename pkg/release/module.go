@@ -0,0 +1,27 @@
+package release
+
+import (
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+)
+
+// ReleaseModule is the execution backend for a Release: given a rendered
+// manifest it talks to a cluster (or anything else) to realize the
+// desired state. ReleaseManager is written against this interface so the
+// in-process ReleaseHandler and the out-of-process GRPCReleaseModule are
+// interchangeable.
+type ReleaseModule interface {
+	// InstallRelease creates release's resources for the first time.
+	InstallRelease(release *releaseapi.Release) error
+	// UpgradeRelease applies release's resources in place of a prior
+	// revision's.
+	UpgradeRelease(release *releaseapi.Release) error
+	// RollbackRelease restores release to previous. previous is nil when
+	// there is no earlier revision; ReleaseHandler then deletes release's
+	// resources only if release.Spec.CleanupOnFail is set.
+	RollbackRelease(release *releaseapi.Release, previous *releaseapi.ReleaseHistory) error
+	// DeleteRelease removes release's resources.
+	DeleteRelease(release *releaseapi.Release) error
+	// ReleaseStatus reports the current status of release's live
+	// resources.
+	ReleaseStatus(release *releaseapi.Release) (string, error)
+}
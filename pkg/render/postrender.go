@@ -0,0 +1,42 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// PostRenderer mutates a fully rendered manifest before it reaches the
+// cluster, e.g. to inject sidecars, apply Kustomize overlays, or run
+// OPA/conftest.
+type PostRenderer interface {
+	Run(manifests []byte) ([]byte, error)
+}
+
+// ExecPostRenderer is a PostRenderer that shells out to a configured
+// binary, passing manifests on stdin and reading the transformed result
+// from stdout.
+type ExecPostRenderer struct {
+	command string
+	args    []string
+}
+
+// NewExecPostRenderer returns an ExecPostRenderer that runs command with
+// args, feeding it the manifest on stdin.
+func NewExecPostRenderer(command string, args ...string) *ExecPostRenderer {
+	return &ExecPostRenderer{command: command, args: args}
+}
+
+// Run pipes manifests into the configured command's stdin and returns
+// its stdout.
+func (r *ExecPostRenderer) Run(manifests []byte) ([]byte, error) {
+	cmd := exec.Command(r.command, r.args...)
+	cmd.Stdin = bytes.NewReader(manifests)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("post-renderer %s: %v: %s", r.command, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
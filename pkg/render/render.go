@@ -0,0 +1,29 @@
+package render
+
+import (
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+)
+
+// Render turns a Release's template into a manifest ready to apply to a
+// cluster. The template is used as the manifest as-is; config values are
+// not substituted into it.
+type Render struct{}
+
+// NewRender creates a Render.
+func NewRender() *Render {
+	return &Render{}
+}
+
+// Render returns release's template as its manifest; release's config is
+// not applied.
+func (r *Render) Render(release *releaseapi.Release) ([]byte, error) {
+	return r.RenderTemplate(release.Spec.Template, release.Spec.Config)
+}
+
+// RenderTemplate returns template unchanged as the manifest; config is
+// accepted but not applied. It is exposed separately from Render so callers
+// rolling back to a previous ReleaseHistory can re-render its recorded
+// template without constructing a synthetic Release.
+func (r *Render) RenderTemplate(template string, config map[string]string) ([]byte, error) {
+	return []byte(template), nil
+}
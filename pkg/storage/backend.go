@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"sort"
+
+	releaseapi "github.com/caicloud/clientset/pkg/apis/release/v1alpha1"
+	releasev1alpha1 "github.com/caicloud/clientset/kubernetes/typed/release/v1alpha1"
+	"github.com/caicloud/release-controller/pkg/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// releaseNameLabel is set by the controller on every ReleaseHistory it
+// creates, so histories belonging to a release can be listed without
+// relying on owner references.
+const releaseNameLabel = "release.caicloud.io/release-name"
+
+// releaseSelector returns the label selector matching every
+// ReleaseHistory owned by the release named name.
+func releaseSelector(name string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{releaseNameLabel: name})
+}
+
+// ReleaseBackend persists a Release's status and its ReleaseHistory
+// revisions in the API server.
+type ReleaseBackend interface {
+	// GetLatestHistory returns the most recent ReleaseHistory for
+	// namespace/name, or nil if the release has never been deployed.
+	GetLatestHistory(namespace, name string) (*releaseapi.ReleaseHistory, error)
+	// SetCondition records condition on release's status.
+	SetCondition(release *releaseapi.Release, condition releaseapi.ReleaseCondition) error
+	// PruneHistory deletes superseded ReleaseHistory objects for
+	// namespace/name, keeping only the max most recent revisions.
+	PruneHistory(namespace, name string, max int) error
+	// ListReleases returns every Release across all namespaces, for
+	// reconciliation passes that need to walk the full set.
+	ListReleases() ([]*releaseapi.Release, error)
+}
+
+// releaseBackend is the default ReleaseBackend, backed by the release
+// CRD API.
+type releaseBackend struct {
+	client releasev1alpha1.ReleaseV1alpha1Interface
+	Log    log.Func
+}
+
+// NewReleaseBackend creates a ReleaseBackend backed by client, logging
+// through logger.
+func NewReleaseBackend(client releasev1alpha1.ReleaseV1alpha1Interface, logger log.Func) ReleaseBackend {
+	return &releaseBackend{client: client, Log: logger}
+}
+
+// GetLatestHistory returns the most recent ReleaseHistory for
+// namespace/name, or nil if the release has never been deployed.
+func (b *releaseBackend) GetLatestHistory(namespace, name string) (*releaseapi.ReleaseHistory, error) {
+	histories, err := b.listHistories(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(histories) == 0 {
+		return nil, nil
+	}
+	return &histories[0], nil
+}
+
+// SetCondition records condition on release's status, replacing any
+// existing condition of the same Type rather than appending, so repeated
+// calls across reconciles don't grow Status.Conditions without bound. It
+// operates on a DeepCopy of release: with concurrent workers processing
+// different releases, release may be the shared object the informer
+// cache returned, which must not be mutated in place.
+func (b *releaseBackend) SetCondition(release *releaseapi.Release, condition releaseapi.ReleaseCondition) error {
+	release = release.DeepCopy()
+	conditions := release.Status.Conditions
+	for i, c := range conditions {
+		if c.Type == condition.Type {
+			conditions[i] = condition
+			_, err := b.client.Releases(release.Namespace).UpdateStatus(release)
+			return err
+		}
+	}
+	release.Status.Conditions = append(conditions, condition)
+	_, err := b.client.Releases(release.Namespace).UpdateStatus(release)
+	return err
+}
+
+// PruneHistory deletes superseded ReleaseHistory objects for
+// namespace/name, keeping only the max most recent revisions. max <= 0
+// disables pruning.
+func (b *releaseBackend) PruneHistory(namespace, name string, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	histories, err := b.listHistories(namespace, name)
+	if err != nil {
+		return err
+	}
+	if len(histories) <= max {
+		return nil
+	}
+	b.Log.Infof("pruning %d superseded history revision(s) for %s/%s", len(histories)-max, namespace, name)
+	for _, history := range histories[max:] {
+		if err := b.client.ReleaseHistories(namespace).Delete(history.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListReleases returns every Release across all namespaces.
+func (b *releaseBackend) ListReleases() ([]*releaseapi.Release, error) {
+	list, err := b.client.Releases(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	releases := make([]*releaseapi.Release, 0, len(list.Items))
+	for i := range list.Items {
+		releases = append(releases, &list.Items[i])
+	}
+	return releases, nil
+}
+
+// listHistories returns the ReleaseHistory objects owned by
+// namespace/name, newest revision first.
+func (b *releaseBackend) listHistories(namespace, name string) ([]releaseapi.ReleaseHistory, error) {
+	list, err := b.client.ReleaseHistories(namespace).List(metav1.ListOptions{
+		LabelSelector: releaseSelector(name).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	histories := list.Items
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].Spec.Revision > histories[j].Spec.Revision
+	})
+	return histories, nil
+}
@@ -8,11 +8,12 @@ import (
 
 // Reasons for releases
 const (
-	ReasonAvailable   = "Available"
-	ReasonFailure     = "Failure"
-	ReasonCreating    = "Creating"
-	ReasonUpdating    = "Updating"
-	ReasonRollbacking = "Rollbacking"
+	ReasonAvailable       = "Available"
+	ReasonFailure         = "Failure"
+	ReasonCreating        = "Creating"
+	ReasonUpdating        = "Updating"
+	ReasonRollbacking     = "Rollbacking"
+	ReasonWaitingForReady = "WaitingForReady"
 )
 
 // ConditionAvailable returns an available condition.
@@ -69,3 +70,17 @@ func ConditionRollbacking() releaseapi.ReleaseCondition {
 		Message:            "",
 	}
 }
+
+// ConditionWaitingForReady returns a condition recording that a release's
+// resources were created or updated successfully and the controller is
+// now waiting for them to satisfy Spec.Wait/Spec.Atomic before the
+// release is considered available.
+func ConditionWaitingForReady(message string) releaseapi.ReleaseCondition {
+	return releaseapi.ReleaseCondition{
+		Type:               releaseapi.ReleaseProgressing,
+		Status:             core.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonWaitingForReady,
+		Message:            message,
+	}
+}